@@ -0,0 +1,322 @@
+package mysql
+
+/*
+	Bulk loading
+
+	Insert/Update (above, in mysql.go) write one row per round trip, which is unusable for data
+	loads. BulkInsert drains a channel of rows into batches, writes each batch as a single
+	multi-row INSERT inside its own transaction, and runs up to BulkOptions.MaxConcurrency
+	batches at once behind a semaphore. Each batch retries through withRetry (see retry.go) so
+	a deadlock or lock-wait timeout doesn't fail the whole load.
+
+	Placeholders go through DB.dialect (see dialect.go) like the rest of the package. BulkConflict's
+	non-error modes do not: IGNORE and ON DUPLICATE KEY UPDATE are MySQL/SQLite syntax with no
+	Postgres equivalent (Postgres expresses the same idea as ON CONFLICT), so those modes are only
+	supported against MySQL/SQLite dialects.
+*/
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// BulkConflictMode controls what happens when a batched row collides with an existing key
+type BulkConflictMode int
+
+const (
+	// BulkConflictError lets the INSERT fail on a duplicate key, same as a plain Insert
+	BulkConflictError BulkConflictMode = iota
+	// BulkConflictIgnoreRow appends IGNORE so duplicate rows are silently skipped
+	BulkConflictIgnoreRow
+	// BulkConflictReplaceByID appends a no-op ON DUPLICATE KEY UPDATE so the row still counts as affected
+	BulkConflictReplaceByID
+	// BulkConflictUpdateColumns appends ON DUPLICATE KEY UPDATE for BulkConflict.Columns
+	BulkConflictUpdateColumns
+)
+
+// BulkConflict describes how BulkInsert should handle a duplicate key. Modes other than
+// BulkConflictError render as MySQL/SQLite syntax (see the package doc above) and are not
+// supported against PostgresDialect.
+type BulkConflict struct {
+	Mode    BulkConflictMode
+	Columns []string
+}
+
+// BulkOptions configures BulkInsert / BulkUpdate
+type BulkOptions struct {
+	BatchSize      int
+	MaxConcurrency int
+	OnConflict     BulkConflict
+	Retry          RetryPolicy
+	Progress       chan<- BulkStats
+}
+
+// BulkStats is emitted on Progress, one value per completed batch
+type BulkStats struct {
+	Batch    int
+	Inserted int64
+	Err      error
+}
+
+func (o BulkOptions) batchSize() int {
+	if o.BatchSize > 0 {
+		return o.BatchSize
+	}
+	return 500
+}
+
+func (o BulkOptions) maxConcurrency() int64 {
+	if o.MaxConcurrency > 0 {
+		return int64(o.MaxConcurrency)
+	}
+	return 4
+}
+
+// BulkInsert drains rows into batches of opts.BatchSize and writes each batch as a single
+// multi-row INSERT, with up to opts.MaxConcurrency batches in flight at once
+func (d *DB) BulkInsert(ctx context.Context, table string, rows <-chan map[string]interface{}, opts BulkOptions) (int64, error) {
+	return d.bulkWrite(ctx, table, rows, opts, false)
+}
+
+// BulkUpdate is the update equivalent of BulkInsert, keying each row by its id column
+func (d *DB) BulkUpdate(ctx context.Context, table string, rows <-chan map[string]interface{}, opts BulkOptions) (int64, error) {
+	return d.bulkWrite(ctx, table, rows, opts, true)
+}
+
+func (d *DB) bulkWrite(ctx context.Context, table string, rows <-chan map[string]interface{}, opts BulkOptions, update bool) (int64, error) {
+	sem := semaphore.NewWeighted(opts.maxConcurrency())
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		total    int64
+		firstErr error
+		batchNum int
+	)
+
+	submit := func(b []map[string]interface{}) {
+		if len(b) == 0 {
+			return
+		}
+		batchNum++
+		n := batchNum
+
+		if err := sem.Acquire(ctx, 1); err != nil {
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = err
+			}
+			mu.Unlock()
+			return
+		}
+
+		wg.Add(1)
+		go func(b []map[string]interface{}, n int) {
+			defer wg.Done()
+			defer sem.Release(1)
+
+			var inserted int64
+			err := withRetry(ctx, opts.Retry, func() error {
+				var e error
+				if update {
+					inserted, e = d.execBulkUpdate(ctx, table, b)
+				} else {
+					inserted, e = d.execBulkInsert(ctx, table, b, opts.OnConflict)
+				}
+				return e
+			})
+
+			if opts.Progress != nil {
+				opts.Progress <- BulkStats{Batch: n, Inserted: inserted, Err: err}
+			}
+
+			mu.Lock()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			total += inserted
+			mu.Unlock()
+		}(b, n)
+	}
+
+	batch := make([]map[string]interface{}, 0, opts.batchSize())
+	for row := range rows {
+		batch = append(batch, row)
+		if len(batch) >= opts.batchSize() {
+			submit(batch)
+			batch = make([]map[string]interface{}, 0, opts.batchSize())
+		}
+	}
+	submit(batch)
+
+	wg.Wait()
+
+	return total, firstErr
+}
+
+// execBulkInsert writes one batch as one multi-row INSERT per distinct key-set found in the
+// batch, all inside a single transaction. Rows are grouped by their exact set of keys first so
+// a row missing (or adding) a column never silently gets a NULL or a dropped value - it just
+// lands in its own group's statement, which reuses the same placeholders for every row in it.
+func (d *DB) execBulkInsert(ctx context.Context, table string, batch []map[string]interface{}, conflict BulkConflict) (int64, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("unable to start a new transaction: %v", err)
+	}
+
+	var total int64
+	for _, rows := range groupByKeySet(batch) {
+		cols := columnsOf(rows[0])
+
+		placeholders := make([]string, len(rows))
+		vals := make([]interface{}, 0, len(rows)*len(cols))
+
+		arg := 1
+		for i, row := range rows {
+			rowPlaceholders := make([]string, len(cols))
+			for j, c := range cols {
+				rowPlaceholders[j] = d.dialect.Placeholder(arg)
+				arg++
+				vals = append(vals, row[c])
+			}
+			placeholders[i] = "(" + strings.Join(rowPlaceholders, ",") + ")"
+		}
+
+		query := fmt.Sprintf("INSERT %sINTO %s (%s) VALUES %s", insertModifier(conflict), table, strings.Join(cols, ","), strings.Join(placeholders, ","))
+		query += onDuplicateClause(conflict)
+
+		res, err := tx.ExecContext(ctx, query, vals...)
+		if err != nil {
+			tx.Rollback()
+			return total, err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return total, err
+		}
+		total += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return total, err
+	}
+
+	return total, nil
+}
+
+// execBulkUpdate writes one batch as individual UPDATE statements inside a single transaction,
+// each keyed by its id column
+func (d *DB) execBulkUpdate(ctx context.Context, table string, batch []map[string]interface{}) (int64, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("unable to start a new transaction: %v", err)
+	}
+
+	var total int64
+	for _, row := range batch {
+		id, ok := row["id"]
+		if !ok {
+			tx.Rollback()
+			return total, fmt.Errorf("bulk update row is missing its id")
+		}
+
+		cols := make([]string, 0, len(row))
+		vals := make([]interface{}, 0, len(row))
+		arg := 1
+		for k, v := range row {
+			if k == "id" {
+				continue
+			}
+			cols = append(cols, k+"="+d.dialect.Placeholder(arg))
+			arg++
+			vals = append(vals, v)
+		}
+		vals = append(vals, id)
+
+		query := fmt.Sprintf("UPDATE %s SET %s WHERE id=%s", table, strings.Join(cols, ","), d.dialect.Placeholder(arg))
+
+		res, err := tx.ExecContext(ctx, query, vals...)
+		if err != nil {
+			tx.Rollback()
+			return total, err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return total, err
+		}
+		total += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return total, err
+	}
+
+	return total, nil
+}
+
+func columnsOf(row map[string]interface{}) []string {
+	cols := make([]string, 0, len(row))
+	for k := range row {
+		cols = append(cols, k)
+	}
+	return cols
+}
+
+// groupByKeySet splits a batch into groups of rows that all share the exact same set of keys,
+// so a single prepared statement's column list is valid for every row in its group. Group order
+// is not significant, since all groups are written inside the same transaction.
+func groupByKeySet(batch []map[string]interface{}) [][]map[string]interface{} {
+	order := make([]string, 0)
+	groups := make(map[string][]map[string]interface{})
+
+	for _, row := range batch {
+		cols := columnsOf(row)
+		sort.Strings(cols)
+		key := strings.Join(cols, ",")
+
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], row)
+	}
+
+	out := make([][]map[string]interface{}, len(order))
+	for i, key := range order {
+		out[i] = groups[key]
+	}
+	return out
+}
+
+func insertModifier(conflict BulkConflict) string {
+	if conflict.Mode == BulkConflictIgnoreRow {
+		return "IGNORE "
+	}
+	return ""
+}
+
+func onDuplicateClause(conflict BulkConflict) string {
+	switch conflict.Mode {
+	case BulkConflictReplaceByID:
+		return " ON DUPLICATE KEY UPDATE id=id"
+	case BulkConflictUpdateColumns:
+		if len(conflict.Columns) == 0 {
+			return ""
+		}
+		sets := make([]string, len(conflict.Columns))
+		for i, c := range conflict.Columns {
+			sets[i] = c + "=VALUES(" + c + ")"
+		}
+		return " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ",")
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,63 @@
+package mysql
+
+import "testing"
+
+func TestOnDuplicateClause(t *testing.T) {
+
+	if c := onDuplicateClause(BulkConflict{Mode: BulkConflictError}); c != "" {
+		t.Errorf("expected no clause for BulkConflictError, got %q", c)
+	}
+
+	if c := onDuplicateClause(BulkConflict{Mode: BulkConflictReplaceByID}); c != " ON DUPLICATE KEY UPDATE id=id" {
+		t.Errorf("unexpected clause for BulkConflictReplaceByID: %q", c)
+	}
+
+	c := onDuplicateClause(BulkConflict{Mode: BulkConflictUpdateColumns, Columns: []string{"name", "status"}})
+	want := " ON DUPLICATE KEY UPDATE name=VALUES(name),status=VALUES(status)"
+	if c != want {
+		t.Errorf("expected %q, got %q", want, c)
+	}
+}
+
+func TestInsertModifier(t *testing.T) {
+
+	if m := insertModifier(BulkConflict{Mode: BulkConflictIgnoreRow}); m != "IGNORE " {
+		t.Errorf("expected IGNORE modifier, got %q", m)
+	}
+
+	if m := insertModifier(BulkConflict{Mode: BulkConflictError}); m != "" {
+		t.Errorf("expected no modifier, got %q", m)
+	}
+}
+
+func TestGroupByKeySet(t *testing.T) {
+
+	batch := []map[string]interface{}{
+		{"id": 1, "name": "a"},
+		{"id": 2, "name": "b", "extra": "x"},
+		{"id": 3, "name": "c"},
+	}
+
+	groups := groupByKeySet(batch)
+
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %v", len(groups))
+	}
+
+	for _, g := range groups {
+		want := len(columnsOf(g[0]))
+		for _, row := range g {
+			if len(columnsOf(row)) != want {
+				t.Errorf("group contains rows with different key sets")
+			}
+		}
+	}
+
+	total := 0
+	for _, g := range groups {
+		total += len(g)
+	}
+	if total != len(batch) {
+		t.Errorf("expected every row to land in exactly one group, got %v of %v", total, len(batch))
+	}
+}
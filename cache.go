@@ -0,0 +1,346 @@
+package mysql
+
+/*
+	Local write-through / read-through cache tables
+
+	CachedTable mirrors a remote table into an in-process map, keyed by id. Both sides must
+	have a lastmod TIMESTAMP column. A background goroutine calls Sync on pollInterval,
+	pulling everything changed since the last watermark and resolving conflicts last-writer-wins
+	by comparing lastmod, then pushing up any rows that were written locally.
+
+	Its essential the local table continues to function if the DB is unavailable - so Fetch,
+	Insert, Update and Delete always operate against the local map first. If a push to the DB
+	fails, Insert/Update queue the row in the dirty set and Delete queues the id in
+	pendingDeletes; both are replayed on the next successful Sync.
+*/
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// lastmodColumn is the column both sides of a CachedTable must carry
+const lastmodColumn = "lastmod"
+
+// syncOverlap re-queries rows modified in the few seconds before the last watermark too, so
+// clock skew or second-resolution truncation between this process and the database's lastmod
+// column never permanently drops a row from every future Sync
+const syncOverlap = 2 * time.Second
+
+// OnConflict overrides the default last-writer-wins resolution for a single row
+type OnConflictFunc func(local, remote map[string]interface{}) map[string]interface{}
+
+// CacheMetrics reports the health of a CachedTable's background sync
+type CacheMetrics struct {
+	SyncLag           time.Duration
+	QueuedWrites      int64
+	ConflictsResolved int64
+}
+
+// CachedTable mirrors a remote MySQL table into an in-process map for read-through /
+// write-through access that keeps working while the database is unreachable
+type CachedTable struct {
+	db           *DB
+	table        string
+	pollInterval time.Duration
+
+	OnConflict OnConflictFunc
+
+	mu             sync.RWMutex
+	rows           map[int64]map[string]interface{}
+	dirty          map[int64]bool
+	pendingDeletes map[int64]bool
+	watermark      time.Time
+
+	metrics struct {
+		sync.Mutex
+		syncLag           time.Duration
+		queuedWrites      int64
+		conflictsResolved int64
+	}
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewCachedTable creates a CachedTable for name and starts a background goroutine that
+// calls Sync every pollInterval until Close is called
+func (d *DB) NewCachedTable(ctx context.Context, name string, pollInterval time.Duration) (*CachedTable, error) {
+	c := &CachedTable{
+		db:             d,
+		table:          name,
+		pollInterval:   pollInterval,
+		rows:           make(map[int64]map[string]interface{}),
+		dirty:          make(map[int64]bool),
+		pendingDeletes: make(map[int64]bool),
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+
+	// prime the local copy, tolerating a DB that is unavailable at startup
+	_ = c.Sync(ctx)
+
+	go c.pollLoop()
+
+	return c, nil
+}
+
+func (c *CachedTable) pollLoop() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			_ = c.Sync(context.Background())
+		}
+	}
+}
+
+// Close stops the background sync goroutine
+func (c *CachedTable) Close() {
+	close(c.stop)
+	<-c.done
+}
+
+// Metrics returns a snapshot of the sync lag, queued write count and conflicts resolved so far
+func (c *CachedTable) Metrics() CacheMetrics {
+	c.metrics.Lock()
+	defer c.metrics.Unlock()
+
+	return CacheMetrics{
+		SyncLag:           c.metrics.syncLag,
+		QueuedWrites:      c.metrics.queuedWrites,
+		ConflictsResolved: c.metrics.conflictsResolved,
+	}
+}
+
+// Sync pulls every row changed since the last watermark, resolves conflicts against the
+// local copy last-writer-wins (or via OnConflict if set), then pushes any locally-dirty
+// rows back up with Insert/Update
+func (c *CachedTable) Sync(ctx context.Context) error {
+	start := time.Now()
+
+	if err := c.db.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("cache table %v: DB unavailable, skipping sync: %v", c.table, err)
+	}
+
+	q := c.db.From(c.table)
+	c.mu.RLock()
+	watermark := c.watermark
+	c.mu.RUnlock()
+	if !watermark.IsZero() {
+		q = q.Where(lastmodColumn, ">", watermark.Add(-syncOverlap))
+	}
+
+	remote, err := q.Select(ctx)
+	if err != nil {
+		return fmt.Errorf("cache table %v: unable to pull changes: %v", c.table, err)
+	}
+
+	c.mu.Lock()
+	for _, row := range remote {
+		id, ok := rowID(row)
+		if !ok {
+			continue
+		}
+		if c.pendingDeletes[id] {
+			// a local delete for this row hasn't replayed to the DB yet - don't let a pull
+			// that ran before the delete replays resurrect it locally
+			continue
+		}
+
+		local, exists := c.rows[id]
+		if !exists {
+			c.rows[id] = row
+			continue
+		}
+
+		resolved := row
+		if remoteBefore(local, row) {
+			c.metrics.Lock()
+			c.metrics.conflictsResolved++
+			c.metrics.Unlock()
+
+			if c.OnConflict != nil {
+				resolved = c.OnConflict(local, row)
+			} else {
+				resolved = local
+			}
+		}
+
+		c.rows[id] = resolved
+	}
+	c.watermark = start
+	c.mu.Unlock()
+
+	// push back anything modified locally while the DB was unavailable or since the last sync
+	c.mu.Lock()
+	pending := make([]int64, 0, len(c.dirty))
+	for id := range c.dirty {
+		pending = append(pending, id)
+	}
+	c.mu.Unlock()
+
+	for _, id := range pending {
+		c.mu.RLock()
+		row := c.rows[id]
+		c.mu.RUnlock()
+
+		var pushErr error
+		if _, _, pushErr = c.db.Insert(row, c.table); pushErr != nil {
+			_, pushErr = c.db.Update(row, c.table)
+		}
+
+		if pushErr == nil {
+			c.mu.Lock()
+			delete(c.dirty, id)
+			c.mu.Unlock()
+		}
+	}
+
+	// replay any deletes that couldn't reach the DB when they were made
+	c.mu.Lock()
+	pendingDel := make([]int64, 0, len(c.pendingDeletes))
+	for id := range c.pendingDeletes {
+		pendingDel = append(pendingDel, id)
+	}
+	c.mu.Unlock()
+
+	for _, id := range pendingDel {
+		if _, err := c.db.Delete(map[string]interface{}{"id": id}, c.table); err == nil {
+			c.mu.Lock()
+			delete(c.pendingDeletes, id)
+			c.mu.Unlock()
+		}
+	}
+
+	c.metrics.Lock()
+	c.metrics.syncLag = time.Since(start)
+	c.metrics.queuedWrites = int64(len(c.dirty) + len(c.pendingDeletes))
+	c.metrics.Unlock()
+
+	return nil
+}
+
+// remoteBefore reports whether local's lastmod is newer than remote's, meaning the remote
+// row lost the last-writer-wins comparison and should be resolved rather than blindly applied
+func remoteBefore(local, remote map[string]interface{}) bool {
+	l, lok := local[lastmodColumn]
+	r, rok := remote[lastmodColumn]
+	if !lok || !rok {
+		return false
+	}
+	return fmt.Sprintf("%v", l) > fmt.Sprintf("%v", r)
+}
+
+func rowID(row map[string]interface{}) (int64, bool) {
+	v, ok := row["id"]
+	if !ok {
+		return 0, false
+	}
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	default:
+		var id int64
+		if _, err := fmt.Sscanf(fmt.Sprintf("%v", n), "%d", &id); err != nil {
+			return 0, false
+		}
+		return id, true
+	}
+}
+
+// Fetch returns the local copy of a row by id, regardless of whether the DB is reachable
+func (c *CachedTable) Fetch(id int64) (map[string]interface{}, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	row, ok := c.rows[id]
+	if !ok {
+		return nil, fmt.Errorf("cache table %v: no row with id %v", c.table, id)
+	}
+	return row, nil
+}
+
+// Insert writes a row to the local copy and marks it dirty for replay to the DB
+func (c *CachedTable) Insert(row map[string]interface{}) (int64, error) {
+	id, ok := rowID(row)
+	if !ok {
+		return 0, fmt.Errorf("cache table %v: row is missing its id", c.table)
+	}
+
+	c.mu.Lock()
+	c.rows[id] = row
+	c.dirty[id] = true
+	c.mu.Unlock()
+
+	if _, _, err := c.db.Insert(row, c.table); err != nil {
+		c.metrics.Lock()
+		c.metrics.queuedWrites++
+		c.metrics.Unlock()
+		return id, nil
+	}
+
+	c.mu.Lock()
+	delete(c.dirty, id)
+	c.mu.Unlock()
+
+	return id, nil
+}
+
+// Update writes a row to the local copy and marks it dirty for replay to the DB
+func (c *CachedTable) Update(row map[string]interface{}) error {
+	id, ok := rowID(row)
+	if !ok {
+		return fmt.Errorf("cache table %v: row is missing its id", c.table)
+	}
+
+	c.mu.Lock()
+	c.rows[id] = row
+	c.dirty[id] = true
+	c.mu.Unlock()
+
+	if _, err := c.db.Update(row, c.table); err != nil {
+		c.metrics.Lock()
+		c.metrics.queuedWrites++
+		c.metrics.Unlock()
+		return nil
+	}
+
+	c.mu.Lock()
+	delete(c.dirty, id)
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Delete removes a row from the local copy immediately and queues the id in pendingDeletes for
+// Sync to replay if the push to the DB fails, mirroring how Insert/Update handle a push failure
+// rather than surfacing it to the caller
+func (c *CachedTable) Delete(id int64) error {
+	c.mu.Lock()
+	delete(c.rows, id)
+	delete(c.dirty, id)
+	c.pendingDeletes[id] = true
+	c.mu.Unlock()
+
+	if _, err := c.db.Delete(map[string]interface{}{"id": id}, c.table); err != nil {
+		c.metrics.Lock()
+		c.metrics.queuedWrites++
+		c.metrics.Unlock()
+		return nil
+	}
+
+	c.mu.Lock()
+	delete(c.pendingDeletes, id)
+	c.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRowID(t *testing.T) {
+
+	id, ok := rowID(map[string]interface{}{"id": int64(42)})
+	if !ok || id != 42 {
+		t.Errorf("expected id 42, got %v (ok=%v)", id, ok)
+	}
+
+	_, ok = rowID(map[string]interface{}{"name": "no id column"})
+	if ok {
+		t.Errorf("expected ok=false for a row with no id")
+	}
+}
+
+func TestRemoteBefore(t *testing.T) {
+
+	local := map[string]interface{}{"lastmod": "2020-01-02 00:00:00"}
+	remote := map[string]interface{}{"lastmod": "2020-01-01 00:00:00"}
+
+	if !remoteBefore(local, remote) {
+		t.Errorf("expected local to win when its lastmod is newer")
+	}
+
+	if remoteBefore(remote, local) {
+		t.Errorf("expected remote to win when its lastmod is newer")
+	}
+}
+
+func TestCachedTableDeleteQueuesForReplayWhenPushFails(t *testing.T) {
+
+	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
+
+	db, e := Connect("root", "", "test", "db")
+	if e != nil {
+		t.Errorf("%v", e)
+	}
+
+	c := &CachedTable{
+		db:             db,
+		table:          "cache_delete_test_missing_table",
+		rows:           map[int64]map[string]interface{}{1: {"id": int64(1)}},
+		dirty:          make(map[int64]bool),
+		pendingDeletes: make(map[int64]bool),
+	}
+
+	if e = c.Delete(1); e != nil {
+		t.Errorf("Delete should succeed locally even when the DB push fails, got %v", e)
+	}
+
+	if _, ok := c.rows[1]; ok {
+		t.Errorf("expected row 1 to be removed from the local copy immediately")
+	}
+	if !c.pendingDeletes[1] {
+		t.Errorf("expected the failed push to be queued in pendingDeletes for replay")
+	}
+	if _, e = c.Fetch(1); e == nil {
+		t.Errorf("expected a deleted row to no longer be fetchable")
+	}
+
+	// point at a table that actually exists and re-sync - the queued delete should replay and
+	// clear from pendingDeletes instead of reappearing locally on the next pull
+	if _, e = db.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS cache_delete_test (id INT PRIMARY KEY, lastmod TIMESTAMP)"); e != nil {
+		t.Errorf("%v", e)
+	}
+	c.table = "cache_delete_test"
+
+	if e = c.Sync(ctx); e != nil {
+		t.Errorf("%v", e)
+	}
+
+	if c.pendingDeletes[1] {
+		t.Errorf("expected the queued delete to replay and clear once the DB accepted it")
+	}
+}
@@ -0,0 +1,102 @@
+package mysql
+
+/*
+	Dialect layer
+
+	The package doc has always claimed this "can be swapped out for postgres or others", but
+	Connect, Insert, Update, Delete, Fetch and Schema all hardcoded the mysql driver and its
+	?/`...`/SET k=?,... syntax. Dialect captures what actually differs between databases so
+	those methods can build SQL generically; ConnectWithDialect picks which one is in use.
+*/
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect is what DB needs to know about a specific database to build correct SQL
+type Dialect interface {
+	// DriverName is passed to sql.Open, e.g. "mysql", "postgres", "sqlite"
+	DriverName() string
+	// Placeholder returns the positional placeholder for argument i (1-based), e.g. "?" or "$3"
+	Placeholder(i int) string
+	// Quote wraps ident in the dialect's identifier quoting, e.g. `ident` or "ident"
+	Quote(ident string) string
+	// InsertSQL builds a full INSERT statement for table given its columns, in column order
+	InsertSQL(table string, cols []string) string
+	// SchemaLogDDL is the CREATE TABLE used by Schema to track applied statements
+	SchemaLogDDL() string
+	// LastInsertIDSupported reports whether sql.Result.LastInsertId works for this dialect
+	LastInsertIDSupported() bool
+}
+
+// MySQLDialect is the dialect this package has always spoken
+type MySQLDialect struct{}
+
+func (MySQLDialect) DriverName() string { return "mysql" }
+
+func (MySQLDialect) Placeholder(i int) string { return "?" }
+
+func (MySQLDialect) Quote(ident string) string { return "`" + ident + "`" }
+
+func (MySQLDialect) InsertSQL(table string, cols []string) string {
+	sets := make([]string, len(cols))
+	for i, c := range cols {
+		sets[i] = c + "=?"
+	}
+	return "INSERT INTO " + table + " SET " + strings.Join(sets, ",")
+}
+
+func (MySQLDialect) SchemaLogDDL() string {
+	return `CREATE TABLE IF NOT EXISTS dbSchemaLog (id int(11) NOT NULL, tx TEXT, lastModify timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(), PRIMARY KEY (id))`
+}
+
+func (MySQLDialect) LastInsertIDSupported() bool { return true }
+
+// PostgresDialect targets Postgres, which uses $N placeholders and RETURNING instead of
+// LastInsertId
+type PostgresDialect struct{}
+
+func (PostgresDialect) DriverName() string { return "postgres" }
+
+func (PostgresDialect) Placeholder(i int) string { return fmt.Sprintf("$%d", i) }
+
+func (PostgresDialect) Quote(ident string) string { return `"` + ident + `"` }
+
+func (PostgresDialect) InsertSQL(table string, cols []string) string {
+	placeholders := make([]string, len(cols))
+	for i := range cols {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return "INSERT INTO " + table + " (" + strings.Join(cols, ",") + ") VALUES (" + strings.Join(placeholders, ",") + ")"
+}
+
+func (PostgresDialect) SchemaLogDDL() string {
+	return `CREATE TABLE IF NOT EXISTS dbSchemaLog (id INTEGER NOT NULL, tx TEXT, lastModify TIMESTAMP NOT NULL DEFAULT now(), PRIMARY KEY (id))`
+}
+
+func (PostgresDialect) LastInsertIDSupported() bool { return false }
+
+// SQLiteDialect targets SQLite, which shares MySQL's ? placeholders but not its INSERT ... SET
+// extension
+type SQLiteDialect struct{}
+
+func (SQLiteDialect) DriverName() string { return "sqlite" }
+
+func (SQLiteDialect) Placeholder(i int) string { return "?" }
+
+func (SQLiteDialect) Quote(ident string) string { return `"` + ident + `"` }
+
+func (SQLiteDialect) InsertSQL(table string, cols []string) string {
+	placeholders := make([]string, len(cols))
+	for i := range placeholders {
+		placeholders[i] = "?"
+	}
+	return "INSERT INTO " + table + " (" + strings.Join(cols, ",") + ") VALUES (" + strings.Join(placeholders, ",") + ")"
+}
+
+func (SQLiteDialect) SchemaLogDDL() string {
+	return `CREATE TABLE IF NOT EXISTS dbSchemaLog (id INTEGER NOT NULL PRIMARY KEY, tx TEXT, lastModify TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)`
+}
+
+func (SQLiteDialect) LastInsertIDSupported() bool { return true }
@@ -0,0 +1,32 @@
+package mysql
+
+import "testing"
+
+func TestDialectInsertSQL(t *testing.T) {
+
+	mysqlSQL := MySQLDialect{}.InsertSQL("mytable", []string{"a", "b"})
+	if mysqlSQL != "INSERT INTO mytable SET a=?,b=?" {
+		t.Errorf("unexpected mysql insert SQL: %v", mysqlSQL)
+	}
+
+	pgSQL := PostgresDialect{}.InsertSQL("mytable", []string{"a", "b"})
+	if pgSQL != "INSERT INTO mytable (a,b) VALUES ($1,$2)" {
+		t.Errorf("unexpected postgres insert SQL: %v", pgSQL)
+	}
+
+	sqliteSQL := SQLiteDialect{}.InsertSQL("mytable", []string{"a", "b"})
+	if sqliteSQL != "INSERT INTO mytable (a,b) VALUES (?,?)" {
+		t.Errorf("unexpected sqlite insert SQL: %v", sqliteSQL)
+	}
+}
+
+func TestDialectPlaceholder(t *testing.T) {
+
+	if p := (PostgresDialect{}).Placeholder(3); p != "$3" {
+		t.Errorf("expected $3, got %v", p)
+	}
+
+	if p := (MySQLDialect{}).Placeholder(3); p != "?" {
+		t.Errorf("expected ?, got %v", p)
+	}
+}
@@ -0,0 +1,392 @@
+package mysql
+
+/*
+	Versioned migrations
+
+	Schema() above reads a flat list of statements and checks they are applied in order.
+	Migrate() replaces that with a directory of numbered, named, reversible migration files,
+	e.g. 0001_init.up.sql / 0001_init.down.sql, each holding one or more statements. Statements
+	are split on migrationDelimiter (";"), except inside a sql-migrate style
+	"-- +migrate StatementBegin" / "-- +migrate StatementEnd" block, which is always kept as one
+	statement regardless of any ";" inside it - e.g. for a trigger or stored procedure body.
+	migrationDelimiter itself is not currently configurable per call; a migration that needs a
+	different statement separator has to express it as a StatementBegin/StatementEnd block.
+
+	A separate dbMigrationLog table stores the sha256 of the up file alongside the version, so a
+	migration that has already run cannot be silently edited and re-applied - this keeps schema
+	identical across every deployment, the same goal Schema() above was written for. It is a
+	distinct table from Schema()'s dbSchemaLog (different columns, same "don't replay history
+	out of order" goal) since the two track incompatible row shapes - they are not meant to be
+	used against the same database at once.
+
+	Migration SQL is very likely to contain DDL, and MySQL DDL causes an implicit commit -
+	the same problem Schema() above is built around (see its "implied commit" comments). So a
+	migration's statements are not wrapped in one transaction: dbMigrationLog.statements_applied
+	is advanced one statement at a time, in the same transaction as the statement that just ran,
+	and applied_at is only set once every statement has succeeded. If a later statement fails,
+	the already-run statements (and their implicit commits) stay applied and recorded, so the
+	next Migrate call resumes after the last completed statement instead of re-running
+	non-idempotent DDL that already happened.
+*/
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// migrationNamePattern matches files named like 0001_init.up.sql / 0001_init.down.sql
+var migrationNamePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// migrationDelimiter separates multiple statements within a single migration file
+const migrationDelimiter = ";"
+
+// migration holds the parsed up/down SQL for a single version
+type migration struct {
+	Version int
+	Name    string
+	UpSQL   string
+	DownSQL string
+}
+
+// MigrationStatus describes a single migration found on disk and whether it has been applied
+type MigrationStatus struct {
+	Version int
+	Name    string
+	Applied bool
+	Hash    string
+}
+
+// loadMigrations reads fsys and pairs up each version's .up.sql and .down.sql files
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read migrations directory: %v", err)
+	}
+
+	byVersion := make(map[int]*migration)
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		m := migrationNamePattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+
+		var version int
+		if _, e := fmt.Sscanf(m[1], "%d", &version); e != nil {
+			return nil, fmt.Errorf("unable to parse migration version from %v: %v", entry.Name(), e)
+		}
+
+		data, e := fs.ReadFile(fsys, entry.Name())
+		if e != nil {
+			return nil, fmt.Errorf("unable to read migration %v: %v", entry.Name(), e)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+
+		if m[3] == "up" {
+			mig.UpSQL = string(data)
+		} else {
+			mig.DownSQL = string(data)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		migrations = append(migrations, *mig)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// migrationStatementBeginMarker and migrationStatementEndMarker bracket a block that must be
+// treated as a single statement even though it contains migrationDelimiter, e.g. a trigger or
+// stored procedure body - the same convention sql-migrate uses
+const migrationStatementBeginMarker = "-- +migrate StatementBegin"
+const migrationStatementEndMarker = "-- +migrate StatementEnd"
+
+// migrationStatements splits a migration file into individual statements. Outside a
+// StatementBegin/StatementEnd block it splits on migrationDelimiter, dropping any blank
+// statements left over from a trailing delimiter or blank lines; inside one, every line up to
+// the matching End marker is kept together as a single statement.
+func migrationStatements(sqlText string) []string {
+	statements := make([]string, 0)
+
+	splitDelimited := func(text string) {
+		for _, p := range strings.Split(text, migrationDelimiter) {
+			p = strings.TrimSpace(p)
+			if p != "" {
+				statements = append(statements, p)
+			}
+		}
+	}
+
+	var outside strings.Builder
+	var block strings.Builder
+	inBlock := false
+
+	for _, line := range strings.Split(sqlText, "\n") {
+		switch strings.TrimSpace(line) {
+		case migrationStatementBeginMarker:
+			splitDelimited(outside.String())
+			outside.Reset()
+			inBlock = true
+			block.Reset()
+
+		case migrationStatementEndMarker:
+			if s := strings.TrimSpace(block.String()); s != "" {
+				statements = append(statements, s)
+			}
+			inBlock = false
+
+		default:
+			if inBlock {
+				block.WriteString(line)
+				block.WriteString("\n")
+			} else {
+				outside.WriteString(line)
+				outside.WriteString("\n")
+			}
+		}
+	}
+	splitDelimited(outside.String())
+
+	return statements
+}
+
+// migrationHash returns the hex-encoded sha256 of a migration's up SQL, used to detect
+// an already-applied migration being edited after the fact
+func migrationHash(sqlText string) string {
+	sum := sha256.Sum256([]byte(sqlText))
+	return hex.EncodeToString(sum[:])
+}
+
+// ensureMigrationsTable creates the version-tracking table used by Migrate/MigrateDown/Status.
+// It is named dbMigrationLog, not dbSchemaLog, because Schema() above already owns a table of
+// that name with an incompatible set of columns - the two are separate tracking mechanisms.
+// applied_at is NULL until every statement in the migration has succeeded; statements_applied
+// tracks how far a partially-run migration got, so a failed migration can be resumed rather than
+// replayed from its first statement.
+func (d *DB) ensureMigrationsTable(ctx context.Context) error {
+	_, err := d.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS dbMigrationLog (version INT PRIMARY KEY, name TEXT, up_sql_sha256 CHAR(64), statements_applied INT NOT NULL DEFAULT 0, applied_at TIMESTAMP NULL)`)
+	if err != nil {
+		return fmt.Errorf("unable to create dbMigrationLog: %v", err)
+	}
+	return nil
+}
+
+// highestAppliedVersion returns the largest version fully applied in dbMigrationLog, or 0 if
+// none have completed yet. A version with applied_at still NULL (a failed, partially-run
+// migration) does not count, since it has not actually finished applying.
+func (d *DB) highestAppliedVersion(ctx context.Context) (int, error) {
+	var maxVersion int
+	err := d.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM dbMigrationLog WHERE applied_at IS NOT NULL").Scan(&maxVersion)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read highest applied version from dbMigrationLog: %v", err)
+	}
+	return maxVersion, nil
+}
+
+// Migrate applies every migration in fsys that is not yet fully recorded in dbMigrationLog, in
+// version order. If a migration that has already been applied no longer matches its stored
+// hash, Migrate stops and returns an error rather than risk divergent schema between
+// deployments. It also refuses to apply a migration numbered lower than one already recorded as
+// applied - e.g. a migration merged late with a stale version number - since silently applying
+// it out of order is exactly the multi-developer race Schema() above was written to prevent.
+//
+// Each statement within a migration runs in its own transaction alongside the progress update
+// that records it, rather than one transaction for the whole migration, because MySQL DDL
+// causes an implicit commit mid-transaction (see the package comment above) - wrapping the
+// whole migration in a single BeginTx/Commit would mean a later statement's failure can't roll
+// back an earlier DDL statement's implicit commit. If a migration fails partway, the next
+// Migrate call resumes at the first statement that didn't yet succeed instead of re-running
+// statements whose DDL has already taken effect.
+func (d *DB) Migrate(ctx context.Context, fsys fs.FS) error {
+	if err := d.ensureMigrationsTable(ctx); err != nil {
+		return err
+	}
+
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return err
+	}
+
+	maxApplied, err := d.highestAppliedVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, mig := range migrations {
+		if mig.UpSQL == "" {
+			return fmt.Errorf("migration %v_%v is missing its .up.sql file", mig.Version, mig.Name)
+		}
+
+		hash := migrationHash(mig.UpSQL)
+		stmts := migrationStatements(mig.UpSQL)
+
+		var storedHash string
+		var doneStatements int
+		var appliedAt sql.NullTime
+		e := d.db.QueryRowContext(ctx, "SELECT up_sql_sha256, statements_applied, applied_at FROM dbMigrationLog WHERE version=?", mig.Version).Scan(&storedHash, &doneStatements, &appliedAt)
+
+		switch {
+		case e == nil:
+			if storedHash != hash {
+				return fmt.Errorf("migration %v has changed since it was applied: stored hash %v, current hash %v", mig.Version, storedHash, hash)
+			}
+			if appliedAt.Valid {
+				continue
+			}
+			// a previous run recorded doneStatements statements before failing - resume there
+			// rather than re-run DDL that already took effect
+
+		case e == sql.ErrNoRows:
+			if mig.Version < maxApplied {
+				return fmt.Errorf("migration %v is out of order: version %v has already been applied", mig.Version, maxApplied)
+			}
+
+			if _, e = d.db.ExecContext(ctx, "INSERT INTO dbMigrationLog (version, name, up_sql_sha256, statements_applied, applied_at) VALUES (?, ?, ?, 0, NULL)", mig.Version, mig.Name, hash); e != nil {
+				return fmt.Errorf("unable to record migration %v in dbMigrationLog: %v", mig.Version, e)
+			}
+			doneStatements = 0
+
+		default:
+			return fmt.Errorf("unable to check dbMigrationLog for version %v: %v", mig.Version, e)
+		}
+
+		for i := doneStatements; i < len(stmts); i++ {
+			tx, e := d.db.BeginTx(ctx, nil)
+			if e != nil {
+				return fmt.Errorf("unable to start a new transaction: %v", e)
+			}
+
+			if _, e = tx.ExecContext(ctx, stmts[i]); e != nil {
+				tx.Rollback()
+				return fmt.Errorf("unable to apply migration %v statement %v: %v", mig.Version, i+1, e)
+			}
+
+			if _, e = tx.ExecContext(ctx, "UPDATE dbMigrationLog SET statements_applied=? WHERE version=?", i+1, mig.Version); e != nil {
+				tx.Rollback()
+				return fmt.Errorf("unable to record progress for migration %v: %v", mig.Version, e)
+			}
+
+			if e = tx.Commit(); e != nil {
+				return fmt.Errorf("unable to commit migration %v statement %v: %v", mig.Version, i+1, e)
+			}
+		}
+
+		if _, e := d.db.ExecContext(ctx, "UPDATE dbMigrationLog SET applied_at=current_timestamp() WHERE version=?", mig.Version); e != nil {
+			return fmt.Errorf("unable to mark migration %v applied: %v", mig.Version, e)
+		}
+
+		if mig.Version > maxApplied {
+			maxApplied = mig.Version
+		}
+	}
+
+	return nil
+}
+
+// MigrateDown reverts every applied migration above toVersion, running each .down.sql in
+// reverse version order and removing its row from dbSchemaLog as it goes
+func (d *DB) MigrateDown(ctx context.Context, fsys fs.FS, toVersion int) error {
+	if err := d.ensureMigrationsTable(ctx); err != nil {
+		return err
+	}
+
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version > migrations[j].Version })
+
+	for _, mig := range migrations {
+		if mig.Version <= toVersion {
+			continue
+		}
+
+		var applied int
+		e := d.db.QueryRowContext(ctx, "SELECT 1 FROM dbMigrationLog WHERE version=? AND applied_at IS NOT NULL", mig.Version).Scan(&applied)
+		if e == sql.ErrNoRows {
+			continue
+		}
+		if e != nil {
+			return fmt.Errorf("unable to check dbMigrationLog for version %v: %v", mig.Version, e)
+		}
+
+		if mig.DownSQL == "" {
+			return fmt.Errorf("migration %v_%v is missing its .down.sql file", mig.Version, mig.Name)
+		}
+
+		tx, e := d.db.BeginTx(ctx, nil)
+		if e != nil {
+			return fmt.Errorf("unable to start a new transaction: %v", e)
+		}
+
+		for _, stmt := range migrationStatements(mig.DownSQL) {
+			if _, e = tx.ExecContext(ctx, stmt); e != nil {
+				tx.Rollback()
+				return fmt.Errorf("unable to revert migration %v: %v", mig.Version, e)
+			}
+		}
+
+		if _, e = tx.ExecContext(ctx, "DELETE FROM dbMigrationLog WHERE version=?", mig.Version); e != nil {
+			tx.Rollback()
+			return fmt.Errorf("unable to remove migration %v from dbMigrationLog: %v", mig.Version, e)
+		}
+
+		if e = tx.Commit(); e != nil {
+			return fmt.Errorf("unable to commit revert of migration %v: %v", mig.Version, e)
+		}
+	}
+
+	return nil
+}
+
+// Status reports every migration found in fsys alongside whether dbMigrationLog shows it applied
+func (d *DB) Status(ctx context.Context, fsys fs.FS) ([]MigrationStatus, error) {
+	if err := d.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, mig := range migrations {
+		var hash string
+		var appliedAt sql.NullTime
+		e := d.db.QueryRowContext(ctx, "SELECT up_sql_sha256, applied_at FROM dbMigrationLog WHERE version=?", mig.Version).Scan(&hash, &appliedAt)
+		if e != nil && e != sql.ErrNoRows {
+			return nil, fmt.Errorf("unable to check dbMigrationLog for version %v: %v", mig.Version, e)
+		}
+
+		statuses = append(statuses, MigrationStatus{
+			Version: mig.Version,
+			Name:    mig.Name,
+			Applied: appliedAt.Valid,
+			Hash:    hash,
+		})
+	}
+
+	return statuses, nil
+}
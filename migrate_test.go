@@ -0,0 +1,208 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLoadMigrations(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"0002_add_email.up.sql":   {Data: []byte("ALTER TABLE users ADD email TEXT;")},
+		"0002_add_email.down.sql": {Data: []byte("ALTER TABLE users DROP email;")},
+		"0001_init.up.sql":        {Data: []byte("CREATE TABLE users (id INT);")},
+		"0001_init.down.sql":      {Data: []byte("DROP TABLE users;")},
+	}
+
+	migrations, e := loadMigrations(fsys)
+	if e != nil {
+		t.Errorf("%v", e)
+	}
+
+	if len(migrations) != 2 {
+		t.Fatalf("expected 2 migrations, got %v", len(migrations))
+	}
+
+	if migrations[0].Version != 1 || migrations[1].Version != 2 {
+		t.Errorf("expected migrations in version order, got %v then %v", migrations[0].Version, migrations[1].Version)
+	}
+
+	if migrations[0].Name != "init" {
+		t.Errorf("expected name init, got %v", migrations[0].Name)
+	}
+}
+
+func TestMigrationStatements(t *testing.T) {
+
+	stmts := migrationStatements("CREATE TABLE a (id INT); ; CREATE TABLE b (id INT);  ")
+
+	if len(stmts) != 2 {
+		t.Fatalf("expected 2 statements, got %v", len(stmts))
+	}
+}
+
+func TestMigrationStatementsKeepsStatementBeginBlockTogether(t *testing.T) {
+
+	sqlText := `CREATE TABLE a (id INT);
+-- +migrate StatementBegin
+CREATE TRIGGER a_trigger BEFORE INSERT ON a FOR EACH ROW BEGIN
+  SET NEW.id = NEW.id;
+END;
+-- +migrate StatementEnd
+CREATE TABLE b (id INT);`
+
+	stmts := migrationStatements(sqlText)
+
+	if len(stmts) != 3 {
+		t.Fatalf("expected 3 statements (before, block, after), got %v: %v", len(stmts), stmts)
+	}
+
+	if !strings.Contains(stmts[1], "CREATE TRIGGER") || !strings.Contains(stmts[1], "END;") {
+		t.Errorf("expected the StatementBegin/End block to stay together as one statement, got %q", stmts[1])
+	}
+}
+
+func TestMigrate(t *testing.T) {
+
+	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
+
+	db, e := Connect("root", "", "test", "db")
+	if e != nil {
+		t.Errorf("%v", e)
+	}
+
+	e = db.Migrate(ctx, fstest.MapFS{
+		"0001_init.up.sql":   {Data: []byte("CREATE TABLE IF NOT EXISTS migrate_test (id INT PRIMARY KEY);")},
+		"0001_init.down.sql": {Data: []byte("DROP TABLE migrate_test;")},
+	})
+	if e != nil {
+		t.Errorf("%v", e)
+	}
+}
+
+func TestMigrateRefusesCorruptedHash(t *testing.T) {
+
+	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
+
+	db, e := Connect("root", "", "test", "db")
+	if e != nil {
+		t.Errorf("%v", e)
+	}
+
+	e = db.Migrate(ctx, fstest.MapFS{
+		"0001_init.up.sql":   {Data: []byte("CREATE TABLE IF NOT EXISTS migrate_hash_test (id INT PRIMARY KEY);")},
+		"0001_init.down.sql": {Data: []byte("DROP TABLE migrate_hash_test;")},
+	})
+	if e != nil {
+		t.Errorf("%v", e)
+	}
+
+	// same version, edited up.sql - the stored hash no longer matches
+	e = db.Migrate(ctx, fstest.MapFS{
+		"0001_init.up.sql":   {Data: []byte("CREATE TABLE IF NOT EXISTS migrate_hash_test (id INT PRIMARY KEY, extra TEXT);")},
+		"0001_init.down.sql": {Data: []byte("DROP TABLE migrate_hash_test;")},
+	})
+	if e == nil {
+		t.Errorf("expected an error when an applied migration's up.sql has changed")
+	}
+}
+
+func TestMigrateRefusesOutOfOrderVersion(t *testing.T) {
+
+	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
+
+	db, e := Connect("root", "", "test", "db")
+	if e != nil {
+		t.Errorf("%v", e)
+	}
+
+	// apply version 2 first, simulating a deployment that only had the later migration
+	e = db.Migrate(ctx, fstest.MapFS{
+		"0002_add_col.up.sql":   {Data: []byte("ALTER TABLE migrate_order_test ADD col TEXT;")},
+		"0002_add_col.down.sql": {Data: []byte("ALTER TABLE migrate_order_test DROP col;")},
+	})
+	if e != nil {
+		t.Errorf("%v", e)
+	}
+
+	// version 1 merged late must not be silently applied after version 2
+	e = db.Migrate(ctx, fstest.MapFS{
+		"0001_init.up.sql":      {Data: []byte("CREATE TABLE IF NOT EXISTS migrate_order_test (id INT PRIMARY KEY);")},
+		"0001_init.down.sql":    {Data: []byte("DROP TABLE migrate_order_test;")},
+		"0002_add_col.up.sql":   {Data: []byte("ALTER TABLE migrate_order_test ADD col TEXT;")},
+		"0002_add_col.down.sql": {Data: []byte("ALTER TABLE migrate_order_test DROP col;")},
+	})
+	if e == nil {
+		t.Errorf("expected an error applying a lower version after a higher one was already applied")
+	}
+}
+
+func TestMigrateResumesAfterNonIdempotentDDLFailure(t *testing.T) {
+
+	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
+
+	db, e := Connect("root", "", "test", "db")
+	if e != nil {
+		t.Errorf("%v", e)
+	}
+
+	if _, e = db.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS migrate_resume_test (id INT PRIMARY KEY)"); e != nil {
+		t.Errorf("%v", e)
+	}
+
+	// the first statement is a non-idempotent ALTER (adding the same column twice is an error),
+	// the second is invalid SQL that always fails
+	fsys := fstest.MapFS{
+		"0001_add_col.up.sql":   {Data: []byte("ALTER TABLE migrate_resume_test ADD COLUMN foo TEXT; NOT VALID SQL HERE;")},
+		"0001_add_col.down.sql": {Data: []byte("ALTER TABLE migrate_resume_test DROP COLUMN foo;")},
+	}
+
+	e = db.Migrate(ctx, fsys)
+	if e == nil {
+		t.Fatalf("expected the invalid second statement to fail")
+	}
+
+	// if Migrate incorrectly re-ran the first statement on retry instead of resuming after it,
+	// this would fail with a duplicate-column error rather than failing again on statement 2
+	e = db.Migrate(ctx, fsys)
+	if e == nil {
+		t.Fatalf("expected the invalid second statement to fail again")
+	}
+	if strings.Contains(strings.ToLower(e.Error()), "duplicate") {
+		t.Errorf("migration re-ran its already-applied first statement instead of resuming: %v", e)
+	}
+}
+
+func TestMigratePartialFailureRollsBack(t *testing.T) {
+
+	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
+
+	db, e := Connect("root", "", "test", "db")
+	if e != nil {
+		t.Errorf("%v", e)
+	}
+
+	// the second statement is invalid, so the whole migration (and its dbMigrationLog row)
+	// must roll back rather than leave the version half-applied
+	e = db.Migrate(ctx, fstest.MapFS{
+		"0001_init.up.sql":   {Data: []byte("CREATE TABLE IF NOT EXISTS migrate_partial_test (id INT PRIMARY KEY); NOT VALID SQL HERE;")},
+		"0001_init.down.sql": {Data: []byte("DROP TABLE migrate_partial_test;")},
+	})
+	if e == nil {
+		t.Errorf("expected an error from the invalid second statement")
+	}
+
+	statuses, e := db.Status(ctx, fstest.MapFS{
+		"0001_init.up.sql":   {Data: []byte("CREATE TABLE IF NOT EXISTS migrate_partial_test (id INT PRIMARY KEY); NOT VALID SQL HERE;")},
+		"0001_init.down.sql": {Data: []byte("DROP TABLE migrate_partial_test;")},
+	})
+	if e != nil {
+		t.Errorf("%v", e)
+	}
+	if len(statuses) == 1 && statuses[0].Applied {
+		t.Errorf("expected the failed migration to not be recorded as applied")
+	}
+}
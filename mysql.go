@@ -23,6 +23,9 @@ package mysql
 	sql.Connect("myusername", "mypassword", "mydb", "127.0.0.1")
 	defer sql.Close()
 
+	// To target postgres or sqlite instead, use ConnectWithDialect with the matching Dialect
+	// from dialect.go, e.g. sql.ConnectWithDialect(mysql.PostgresDialect{}, dsn)
+
 
 	// Old style Query
 	// This risks SQL injection attacks but allows you to write any SQL statement
@@ -77,19 +80,29 @@ import (
 // this is a pointer to db not a value
 type DB struct {
 	db *sql.DB
+	dialect Dialect
 	schemaChanges int
 	schemaFail bool
 }
 
 // Connect function opens a new database connection this must be done before reading/writing to the database
+// It is a thin wrapper around ConnectWithDialect that always picks MySQLDialect, kept so existing callers
+// don't need to change.
 func Connect(username, password, database, host string) (*DB, error) {
+	return ConnectWithDialect(MySQLDialect{}, username+":"+password+"@("+host+")/"+database)
+}
+
+// ConnectWithDialect opens a new database connection using dialect to build SQL, so the same
+// DB, Insert, Update, Delete, Fetch and Schema can target MySQL, Postgres or SQLite. The caller
+// is responsible for blank-importing the matching database/sql driver, same as any database/sql user.
+func ConnectWithDialect(dialect Dialect, dsn string) (*DB, error) {
 	// Attempt to open the database connection
 	var (
 		err error
-		this = new(DB)
+		this = &DB{dialect: dialect}
 	)
 
-	this.db, err = sql.Open("mysql", username+":"+password+"@("+host+")/"+database)
+	this.db, err = sql.Open(dialect.DriverName(), dsn)
 	if err != nil {
 		return nil, errors.New("Unable to connect to DB" + err.Error())
 	}
@@ -131,7 +144,7 @@ func (d *DB) Schema(ctx context.Context, schema io.Reader) error {
 		return fmt.Errorf("unable to start a new transaction: %v", e)
 	}
 
-	if _, e = tx.Exec(`CREATE TABLE IF NOT EXISTS dbSchemaLog (id int(11) NOT NULL, tx TEXT, lastModify timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(), PRIMARY KEY (id))`); e != nil {
+	if _, e = tx.Exec(d.dialect.SchemaLogDDL()); e != nil {
 		return fmt.Errorf("unable to create dbSchemaLog: %v", e)
 	}
 	
@@ -267,34 +280,44 @@ TRUNCATE TABLE
 
 
 // Fetch will do a select query and return as string or int
+// It calls FetchCtx with context.Background(), kept for callers that predate context support.
 func (d *DB) Fetch(where map[string]interface{}, table string) (map[int]map[string]interface{}, error) {
+	return d.FetchCtx(context.Background(), where, table)
+}
+
+// FetchCtx is Fetch with a context, so a caller can cancel or time out a slow select
+func (d *DB) FetchCtx(ctx context.Context, where map[string]interface{}, table string) (map[int]map[string]interface{}, error) {
 
 	// start to build the insert statement
-	i := 0
-	sql := "SELECT * FROM " + table + " WHERE "
-	// Prepare style SQL has placeholders ? for values and the values are added seporately
+	i := 1
+	sql := "SELECT * FROM " + table
+	// Prepare style SQL has placeholders built by the dialect (? for MySQL/SQLite, $N for Postgres)
 	// create an interface for the values as they may be any data type
-	whereVals := make([]interface{}, len(where))
+	whereVals := make([]interface{}, 0, len(where))
+	conditions := make([]string, 0, len(where))
 
 	// Loop adding the (k)ey and (v)alue pairs to the statements
 	for k, v := range where {
-		sql = sql + k + "=? AND "
-		whereVals[i] = v
+		conditions = append(conditions, k+"="+d.dialect.Placeholder(i))
+		whereVals = append(whereVals, v)
 		i++
 	}
 
-	// trim that excess ,
-	query := strings.TrimSuffix(sql, " AND ")
+	if len(conditions) > 0 {
+		sql = sql + " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := sql
 
 	// Execute the query (the easy bit)
-	stmt, err := d.db.Prepare(query)
+	stmt, err := d.db.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
 	// Pull a list of rows
-	rows, err := stmt.Query(whereVals...)
+	rows, err := stmt.QueryContext(ctx, whereVals...)
 	if err != nil {
 		return nil, err
 	}
@@ -347,9 +370,15 @@ func (d *DB) Fetch(where map[string]interface{}, table string) (map[int]map[stri
 }
 
 // FetchAny uses the old style query giving more freedom but insecure, all returns are strings
+// It calls FetchAnyCtx with context.Background(), kept for callers that predate context support.
 func (d *DB) FetchAny(query string) (map[int]map[string]string, error) {
+	return d.FetchAnyCtx(context.Background(), query)
+}
+
+// FetchAnyCtx is FetchAny with a context, so a caller can cancel or time out a slow query
+func (d *DB) FetchAnyCtx(ctx context.Context, query string) (map[int]map[string]string, error) {
 	// Execute the query (the easy bit)
-	rows, err := d.db.Query(query)
+	rows, err := d.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -400,27 +429,38 @@ func (d *DB) FetchAny(query string) (map[int]map[string]string, error) {
 
 // Insert will insert the records, and return an error if there is a problem.
 // it will return the new inserted ID and a count of records affected
+// It calls InsertCtx with context.Background(), kept for callers that predate context support.
 func (d *DB) Insert(updates map[string]interface{}, table string) (id int64, count int64, err error) {
+	return d.InsertCtx(context.Background(), updates, table)
+}
 
-	// start to build the insert statement
-	i := 0
-	sql := "INSERT INTO " + table + " SET "
-	// Prepare style SQL has placeholders ? for values and the values are added seporately
-	// create an interface for the values as they may be any data type
-	vals := make([]interface{}, len(updates))
+// InsertCtx is Insert with a context, so a caller can cancel or time out a slow insert
+func (d *DB) InsertCtx(ctx context.Context, updates map[string]interface{}, table string) (id int64, count int64, err error) {
+
+	// start to build the insert statement, column order and value order must match
+	cols := make([]string, 0, len(updates))
+	vals := make([]interface{}, 0, len(updates))
 
 	// Loop adding the (k)ey and (v)alue pairs to the statements
 	for k, v := range updates {
-		sql = sql + k + "=?,"
-		vals[i] = v
-		i++
+		cols = append(cols, k)
+		vals = append(vals, v)
 	}
 
-	// trim that excess ,
-	query := strings.TrimSuffix(sql, ",")
+	// the dialect decides what the insert statement actually looks like
+	query := d.dialect.InsertSQL(table, cols)
+
+	// Postgres has no LastInsertId, so ask the dialect for the row back instead
+	if !d.dialect.LastInsertIDSupported() {
+		var newID int64
+		if err = d.db.QueryRowContext(ctx, query+" RETURNING id", vals...).Scan(&newID); err != nil {
+			return 0, 0, err
+		}
+		return newID, 1, nil
+	}
 
 	// execute the statement with vals values
-	res, err := d.db.Exec(query, vals...)
+	res, err := d.db.ExecContext(ctx, query, vals...)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -442,37 +482,44 @@ func (d *DB) Insert(updates map[string]interface{}, table string) (id int64, cou
 
 // Update will update the record, and return an error if there is a problem.
 // it will return the count of records affected
+// It calls UpdateCtx with context.Background(), kept for callers that predate context support.
 func (d *DB) Update(updates map[string]interface{}, table string) (count int64, err error) {
+	return d.UpdateCtx(context.Background(), updates, table)
+}
+
+// UpdateCtx is Update with a context, so a caller can cancel or time out a slow update
+func (d *DB) UpdateCtx(ctx context.Context, updates map[string]interface{}, table string) (count int64, err error) {
 	var id interface{}
 
 	// Start to build the update statement
-	i := 0
-	sql := "UPDATE " + table + " SET "
-	// Prepare style SQL has placeholders ? for values and the values are added seporately
-	// create an interface for the values as they may be any data type
-	vals := make([]interface{}, len(updates))
+	cols := make([]string, 0, len(updates))
+	vals := make([]interface{}, 0, len(updates))
 
 	// Loop adding the (k)ey and (v)alue pairs to the statements
 	for k, v := range updates {
 		if k == "id" {
 			id = v
 		} else {
-			sql = sql + k + "=?,"
-			vals[i] = v
-			i++
+			cols = append(cols, k)
+			vals = append(vals, v)
 		}
 	}
 
-	// trim the excess ,
-	query := strings.TrimSuffix(sql, ",") + " WHERE id=?"
-	vals[i] = id
-
 	if id == "" {
-		return 0, fmt.Errorf("Row [%v] is missing the ID, please always include a row ID column", i)
+		return 0, fmt.Errorf("Row [%v] is missing the ID, please always include a row ID column", table)
 	}
 
+	// placeholders are numbered by the dialect (? for MySQL/SQLite, $N for Postgres)
+	sets := make([]string, len(cols))
+	for i, c := range cols {
+		sets[i] = c + "=" + d.dialect.Placeholder(i+1)
+	}
+	vals = append(vals, id)
+
+	query := "UPDATE " + table + " SET " + strings.Join(sets, ",") + " WHERE id=" + d.dialect.Placeholder(len(cols)+1)
+
 	// execute the statement with vals values
-	res, err := d.db.Exec(query, vals...)
+	res, err := d.db.ExecContext(ctx, query, vals...)
 	if err != nil {
 		return 0, err
 	}
@@ -488,17 +535,23 @@ func (d *DB) Update(updates map[string]interface{}, table string) (count int64,
 
 // Delete will remove the records, and return an error if there is a problem.
 // It expects a unique field called ID, and returns a count of records affected
+// It calls DeleteCtx with context.Background(), kept for callers that predate context support.
 func (d *DB) Delete(updates map[string]interface{}, table string) (count int64, err error) {
+	return d.DeleteCtx(context.Background(), updates, table)
+}
+
+// DeleteCtx is Delete with a context, so a caller can cancel or time out a slow delete
+func (d *DB) DeleteCtx(ctx context.Context, updates map[string]interface{}, table string) (count int64, err error) {
 
 	// Prepare style SQL has placeholders ? for values and the values are added seporately
 	// create an interface for the values as they may be any data type
 	vals := make([]interface{}, 1)
 
 	vals[0] = updates["id"]
-	query := "DELETE FROM " + table + " WHERE id=?"
+	query := "DELETE FROM " + table + " WHERE id=" + d.dialect.Placeholder(1)
 
 	// execute the statement with vals values
-	res, err := d.db.Exec(query, vals...)
+	res, err := d.db.ExecContext(ctx, query, vals...)
 	if err != nil {
 		return 0, err
 	}
@@ -524,16 +577,7 @@ func (d *DB) Delete(updates map[string]interface{}, table string) (count int64,
 
 	Multiple read replicas are fine
 
-	Its essential the local table continues to function if the DB is unavailable. 
-*/
-
-
-// func (d *DB) CreateCacheTable() *CachedTable, error {}
-
-// func (c *CachedTable) Sync() error {}
+	Its essential the local table continues to function if the DB is unavailable.
 
-
-
-// type CachedTable struct {
-
-// }
+	See cache.go for CachedTable / NewCachedTable / Sync.
+*/
@@ -0,0 +1,51 @@
+package mysql
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRetryDBDeadlock runs two goroutines updating the same two rows in opposite order, which
+// reliably provokes MySQL error 1213 (deadlock). WithRetry should recover both without the
+// caller seeing an error.
+func TestRetryDBDeadlock(t *testing.T) {
+
+	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
+
+	db, e := Connect("root", "", "test", "db")
+	if e != nil {
+		t.Errorf("%v", e)
+	}
+
+	rdb := db.WithRetry(RetryPolicy{MaxRetries: 5, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 200 * time.Millisecond})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	var err1, err2 error
+
+	go func() {
+		defer wg.Done()
+		_, _, err1 = rdb.InsertCtx(ctx, map[string]interface{}{"id": 1, "val": "a"}, "deadlock_test")
+		_, err1 = rdb.UpdateCtx(ctx, map[string]interface{}{"id": 1, "val": "1a"}, "deadlock_test")
+		_, err1 = rdb.UpdateCtx(ctx, map[string]interface{}{"id": 2, "val": "1b"}, "deadlock_test")
+	}()
+
+	go func() {
+		defer wg.Done()
+		_, _, err2 = rdb.InsertCtx(ctx, map[string]interface{}{"id": 2, "val": "b"}, "deadlock_test")
+		_, err2 = rdb.UpdateCtx(ctx, map[string]interface{}{"id": 2, "val": "2b"}, "deadlock_test")
+		_, err2 = rdb.UpdateCtx(ctx, map[string]interface{}{"id": 1, "val": "2a"}, "deadlock_test")
+	}()
+
+	wg.Wait()
+
+	if err1 != nil {
+		t.Errorf("%v", err1)
+	}
+	if err2 != nil {
+		t.Errorf("%v", err2)
+	}
+}
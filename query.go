@@ -0,0 +1,321 @@
+package mysql
+
+/*
+	Query builder
+
+	Fetch (in mysql.go) can only express ANDed equality. QueryBuilder adds typed WHERE
+	operators, ordering, paging and OR conditions while keeping every value behind a dialect
+	placeholder (? for MySQL/SQLite, $N for Postgres, see dialect.go) - nothing here ever
+	concatenates a value into the SQL string.
+
+	Usage:
+	db.From("mytable").Where("age", ">=", 18).Where("status", "IN", []string{"a", "b"}).
+		OrderBy("created", "DESC").Limit(50).Select(ctx)
+*/
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// whereCondition is a single WHERE clause built up by Where/OrWhere
+type whereCondition struct {
+	or    bool
+	col   string
+	op    string
+	value interface{}
+}
+
+// QueryBuilder builds a SELECT (or UPDATE ... WHERE) statement a clause at a time
+type QueryBuilder struct {
+	db      *DB
+	table   string
+	wheres  []whereCondition
+	order   string
+	orderBy string
+	limit   int
+	offset  int
+}
+
+// From starts a QueryBuilder against table. A DB built via Connect/ConnectWithDialect always
+// carries a dialect, but a zero-value DB (e.g. in a test) does not - default it to MySQLDialect,
+// the same default Connect itself picks, rather than let render's dialect.Placeholder calls
+// dereference a nil interface.
+func (d *DB) From(table string) *QueryBuilder {
+	if d.dialect == nil {
+		d.dialect = MySQLDialect{}
+	}
+	return &QueryBuilder{db: d, table: table}
+}
+
+// Where ANDs a condition onto the query. Supported ops: =, !=, <, <=, >, >=, LIKE, IN,
+// NOT IN, BETWEEN, IS NULL
+func (q *QueryBuilder) Where(col, op string, value interface{}) *QueryBuilder {
+	q.wheres = append(q.wheres, whereCondition{col: col, op: strings.ToUpper(op), value: value})
+	return q
+}
+
+// OrWhere is Where, but joined to the preceding condition with OR instead of AND
+func (q *QueryBuilder) OrWhere(col, op string, value interface{}) *QueryBuilder {
+	q.wheres = append(q.wheres, whereCondition{or: true, col: col, op: strings.ToUpper(op), value: value})
+	return q
+}
+
+// OrderBy sets ORDER BY col dir, e.g. OrderBy("created", "DESC")
+func (q *QueryBuilder) OrderBy(col, dir string) *QueryBuilder {
+	q.orderBy = col
+	q.order = strings.ToUpper(dir)
+	return q
+}
+
+// Limit sets LIMIT n
+func (q *QueryBuilder) Limit(n int) *QueryBuilder {
+	q.limit = n
+	return q
+}
+
+// Offset sets OFFSET n
+func (q *QueryBuilder) Offset(n int) *QueryBuilder {
+	q.offset = n
+	return q
+}
+
+// SQL renders the built query and its positional arguments, useful for debugging
+func (q *QueryBuilder) SQL() (string, []interface{}) {
+	return q.build("SELECT * FROM "+q.table, 1)
+}
+
+// build renders the WHERE/ORDER BY/LIMIT/OFFSET tail onto head, numbering placeholders from
+// startArg so a caller that already consumed some placeholders (e.g. UpdateWhere's SET clause)
+// can continue the same sequence Postgres needs
+func (q *QueryBuilder) build(head string, startArg int) (string, []interface{}) {
+	var sqlStr strings.Builder
+	sqlStr.WriteString(head)
+
+	args := make([]interface{}, 0, len(q.wheres)*2)
+	next := startArg
+
+	for i, w := range q.wheres {
+		if i == 0 {
+			sqlStr.WriteString(" WHERE ")
+		} else if w.or {
+			sqlStr.WriteString(" OR ")
+		} else {
+			sqlStr.WriteString(" AND ")
+		}
+
+		clause, clauseArgs := w.render(q.db.dialect, &next)
+		sqlStr.WriteString(clause)
+		args = append(args, clauseArgs...)
+	}
+
+	if q.orderBy != "" {
+		sqlStr.WriteString(" ORDER BY " + q.orderBy)
+		if q.order != "" {
+			sqlStr.WriteString(" " + q.order)
+		}
+	}
+
+	if q.limit > 0 {
+		sqlStr.WriteString(fmt.Sprintf(" LIMIT %d", q.limit))
+	}
+
+	if q.offset > 0 {
+		sqlStr.WriteString(fmt.Sprintf(" OFFSET %d", q.offset))
+	}
+
+	return sqlStr.String(), args
+}
+
+// render turns one whereCondition into a SQL fragment and its placeholder arguments, drawing
+// placeholders from dialect and advancing next as each one is consumed
+func (w whereCondition) render(dialect Dialect, next *int) (string, []interface{}) {
+	ph := func() string {
+		p := dialect.Placeholder(*next)
+		*next++
+		return p
+	}
+
+	switch w.op {
+	case "IS NULL":
+		return w.col + " IS NULL", nil
+
+	case "IN", "NOT IN":
+		vals := toSlice(w.value)
+		placeholders := make([]string, len(vals))
+		for i := range placeholders {
+			placeholders[i] = ph()
+		}
+		return fmt.Sprintf("%s %s (%s)", w.col, w.op, strings.Join(placeholders, ",")), vals
+
+	case "BETWEEN":
+		vals := toSlice(w.value)
+		return w.col + " BETWEEN " + ph() + " AND " + ph(), vals
+
+	default:
+		return w.col + " " + w.op + " " + ph(), []interface{}{w.value}
+	}
+}
+
+// toSlice normalises a []interface{}/[]string/[]int/... into []interface{} for expansion
+func toSlice(v interface{}) []interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return []interface{}{v}
+	}
+
+	out := make([]interface{}, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out
+}
+
+// Select runs the built query, returning the same map[int]map[string]interface{} shape as Fetch
+func (q *QueryBuilder) Select(ctx context.Context) (map[int]map[string]interface{}, error) {
+	query, args := q.SQL()
+
+	rows, err := q.db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRows(rows)
+}
+
+// scanRows is the shared row-to-map loop used by Select (and originally by Fetch)
+func scanRows(rows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Columns() ([]string, error)
+}) (map[int]map[string]interface{}, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	vals := make([]interface{}, len(cols))
+	allRows := make(map[int]map[string]interface{})
+
+	r := 0
+	for rows.Next() {
+		for k := range cols {
+			vals[k] = &vals[k]
+		}
+
+		if err = rows.Scan(vals...); err != nil {
+			return nil, err
+		}
+
+		allRows[r] = make(map[string]interface{})
+		for k, v := range vals {
+			switch t := v.(type) {
+			case int64:
+				allRows[r][cols[k]] = t
+			case nil:
+				allRows[r][cols[k]] = nil
+			default:
+				allRows[r][cols[k]] = string(v.([]byte))
+			}
+		}
+		r++
+	}
+
+	return allRows, nil
+}
+
+// Scan runs the built query and fills dest, a pointer to a slice of structs. Columns are
+// matched to fields by a `db:"col"` tag first, falling back to a case-insensitive field name.
+func (q *QueryBuilder) Scan(ctx context.Context, dest interface{}) error {
+	query, args := q.SQL()
+
+	rows, err := q.db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	destVal := reflect.ValueOf(dest)
+	if destVal.Kind() != reflect.Ptr || destVal.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("Scan requires a pointer to a slice, got %T", dest)
+	}
+
+	sliceVal := destVal.Elem()
+	elemType := sliceVal.Type().Elem()
+
+	for rows.Next() {
+		elem := reflect.New(elemType).Elem()
+		fieldByCol := columnFields(elemType)
+
+		scanVals := make([]interface{}, len(cols))
+		for i, c := range cols {
+			if field, ok := fieldByCol[strings.ToLower(c)]; ok {
+				scanVals[i] = elem.FieldByIndex(field).Addr().Interface()
+			} else {
+				var discard interface{}
+				scanVals[i] = &discard
+			}
+		}
+
+		if err = rows.Scan(scanVals...); err != nil {
+			return err
+		}
+
+		sliceVal.Set(reflect.Append(sliceVal, elem))
+	}
+
+	return nil
+}
+
+// columnFields maps a lower-cased column name to the struct field that should receive it,
+// preferring a `db:"col"` tag over the field's own name
+func columnFields(t reflect.Type) map[string][]int {
+	fields := make(map[string][]int)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name := strings.ToLower(f.Name)
+		if tag := f.Tag.Get("db"); tag != "" {
+			name = strings.ToLower(tag)
+		}
+
+		fields[name] = f.Index
+	}
+	return fields
+}
+
+// UpdateWhere updates every row matching the built WHERE clause, unlike Update which can
+// only target a single row by id. It requires at least one Where/OrWhere condition - without
+// one, "UPDATE table SET ..." has no WHERE clause at all and would silently rewrite every row.
+func (q *QueryBuilder) UpdateWhere(ctx context.Context, updates map[string]interface{}) (int64, error) {
+	if len(q.wheres) == 0 {
+		return 0, fmt.Errorf("UpdateWhere on %v requires at least one Where/OrWhere condition", q.table)
+	}
+
+	cols := make([]string, 0, len(updates))
+	args := make([]interface{}, 0, len(updates))
+	next := 1
+	for k, v := range updates {
+		cols = append(cols, k+"="+q.db.dialect.Placeholder(next))
+		args = append(args, v)
+		next++
+	}
+
+	head := "UPDATE " + q.table + " SET " + strings.Join(cols, ",")
+	query, whereArgs := q.build(head, next)
+
+	res, err := q.db.db.ExecContext(ctx, query, append(args, whereArgs...)...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
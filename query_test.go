@@ -0,0 +1,82 @@
+package mysql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQueryBuilderSQL(t *testing.T) {
+
+	d := &DB{}
+
+	query, args := d.From("mytable").
+		Where("age", ">=", 18).
+		Where("status", "IN", []string{"a", "b"}).
+		OrWhere("vip", "=", true).
+		OrderBy("created", "DESC").
+		Limit(50).
+		Offset(100).
+		SQL()
+
+	want := "SELECT * FROM mytable WHERE age >= ? AND status IN (?,?) OR vip = ? ORDER BY created DESC LIMIT 50 OFFSET 100"
+	if query != want {
+		t.Errorf("expected %q, got %q", want, query)
+	}
+
+	if len(args) != 4 {
+		t.Fatalf("expected 4 args, got %v (%v)", len(args), args)
+	}
+}
+
+func TestQueryBuilderIsNullAndBetween(t *testing.T) {
+
+	d := &DB{}
+
+	query, args := d.From("mytable").
+		Where("deleted_at", "IS NULL", nil).
+		Where("age", "BETWEEN", []interface{}{18, 30}).
+		SQL()
+
+	want := "SELECT * FROM mytable WHERE deleted_at IS NULL AND age BETWEEN ? AND ?"
+	if query != want {
+		t.Errorf("expected %q, got %q", want, query)
+	}
+
+	if len(args) != 2 {
+		t.Fatalf("expected 2 args, got %v", len(args))
+	}
+}
+
+func TestUpdateWhereRequiresCondition(t *testing.T) {
+
+	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
+
+	db, e := Connect("root", "", "test", "db")
+	if e != nil {
+		t.Errorf("%v", e)
+	}
+
+	_, e = db.From("update_where_test").UpdateWhere(ctx, map[string]interface{}{"name": "x"})
+	if e == nil {
+		t.Errorf("expected an error updating with no Where condition")
+	}
+}
+
+func TestColumnFields(t *testing.T) {
+
+	type row struct {
+		ID   int64  `db:"id"`
+		Name string
+	}
+
+	fields := columnFields(reflect.TypeOf(row{}))
+
+	if _, ok := fields["id"]; !ok {
+		t.Errorf("expected id to map via db tag")
+	}
+	if _, ok := fields["name"]; !ok {
+		t.Errorf("expected name to map via lowercased field name")
+	}
+}
@@ -0,0 +1,88 @@
+package mysql
+
+/*
+	Retry policy shared by BulkInsert/BulkUpdate (see bulk.go) and the *Ctx methods' automatic
+	deadlock recovery (see mysql.go). A transient MySQL deadlock (error 1213) or lock wait
+	timeout (1205) is retried with exponential backoff and jitter rather than bubbled straight
+	out to the caller.
+*/
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// RetryPolicy configures the exponential backoff used to retry a transient MySQL error
+type RetryPolicy struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	MaxElapsed     time.Duration
+}
+
+// DefaultRetryPolicy retries a handful of times with a short exponential backoff
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     5,
+	InitialBackoff: 50 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	MaxElapsed:     10 * time.Second,
+}
+
+// MySQL error numbers worth retrying automatically
+const (
+	errDeadlock        = 1213
+	errLockWaitTimeout = 1205
+)
+
+// isRetryableError reports whether err is a deadlock or lock-wait-timeout from MySQL
+func isRetryableError(err error) bool {
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return false
+	}
+	return myErr.Number == errDeadlock || myErr.Number == errLockWaitTimeout
+}
+
+// withRetry runs fn, retrying on a retryable MySQL error with exponential backoff and jitter,
+// until policy.MaxRetries is hit or policy.MaxElapsed has passed since the first attempt
+func withRetry(ctx context.Context, policy RetryPolicy, fn func() error) error {
+	if policy.InitialBackoff <= 0 {
+		policy = DefaultRetryPolicy
+	}
+
+	start := time.Now()
+	backoff := policy.InitialBackoff
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		if err == nil || !isRetryableError(err) {
+			return err
+		}
+
+		if attempt >= policy.MaxRetries {
+			return err
+		}
+		if policy.MaxElapsed > 0 && time.Since(start) >= policy.MaxElapsed {
+			return err
+		}
+
+		sleep := backoff
+		if policy.MaxBackoff > 0 && sleep > policy.MaxBackoff {
+			sleep = policy.MaxBackoff
+		}
+		sleep = sleep/2 + time.Duration(rand.Int63n(int64(sleep/2)+1))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sleep):
+		}
+
+		backoff *= 2
+	}
+}
@@ -0,0 +1,75 @@
+package mysql
+
+/*
+	DB.WithRetry wraps the *Ctx CRUD methods (see mysql.go) so a transient MySQL deadlock
+	(1213) or lock-wait timeout (1205) is retried with backoff instead of bubbling straight out
+	to the caller, using the same withRetry loop BulkInsert/BulkUpdate use (see retry.go).
+*/
+
+import "context"
+
+// RetryDB is a DB wrapped with a RetryPolicy; obtained from DB.WithRetry
+type RetryDB struct {
+	*DB
+	policy RetryPolicy
+}
+
+// WithRetry returns a RetryDB that retries every *Ctx call against policy
+func (d *DB) WithRetry(policy RetryPolicy) *RetryDB {
+	return &RetryDB{DB: d, policy: policy}
+}
+
+// FetchCtx retries DB.FetchCtx per r.policy
+func (r *RetryDB) FetchCtx(ctx context.Context, where map[string]interface{}, table string) (map[int]map[string]interface{}, error) {
+	var result map[int]map[string]interface{}
+	err := withRetry(ctx, r.policy, func() error {
+		var e error
+		result, e = r.DB.FetchCtx(ctx, where, table)
+		return e
+	})
+	return result, err
+}
+
+// FetchAnyCtx retries DB.FetchAnyCtx per r.policy
+func (r *RetryDB) FetchAnyCtx(ctx context.Context, query string) (map[int]map[string]string, error) {
+	var result map[int]map[string]string
+	err := withRetry(ctx, r.policy, func() error {
+		var e error
+		result, e = r.DB.FetchAnyCtx(ctx, query)
+		return e
+	})
+	return result, err
+}
+
+// InsertCtx retries DB.InsertCtx per r.policy
+func (r *RetryDB) InsertCtx(ctx context.Context, updates map[string]interface{}, table string) (int64, int64, error) {
+	var id, count int64
+	err := withRetry(ctx, r.policy, func() error {
+		var e error
+		id, count, e = r.DB.InsertCtx(ctx, updates, table)
+		return e
+	})
+	return id, count, err
+}
+
+// UpdateCtx retries DB.UpdateCtx per r.policy
+func (r *RetryDB) UpdateCtx(ctx context.Context, updates map[string]interface{}, table string) (int64, error) {
+	var count int64
+	err := withRetry(ctx, r.policy, func() error {
+		var e error
+		count, e = r.DB.UpdateCtx(ctx, updates, table)
+		return e
+	})
+	return count, err
+}
+
+// DeleteCtx retries DB.DeleteCtx per r.policy
+func (r *RetryDB) DeleteCtx(ctx context.Context, updates map[string]interface{}, table string) (int64, error) {
+	var count int64
+	err := withRetry(ctx, r.policy, func() error {
+		var e error
+		count, e = r.DB.DeleteCtx(ctx, updates, table)
+		return e
+	})
+	return count, err
+}
@@ -0,0 +1,46 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestWithRetryRecoversFromDeadlock(t *testing.T) {
+
+	attempts := 0
+	err := withRetry(context.Background(), RetryPolicy{MaxRetries: 3, InitialBackoff: time.Millisecond}, func() error {
+		attempts++
+		if attempts < 3 {
+			return &mysql.MySQLError{Number: errDeadlock}
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %v", attempts)
+	}
+}
+
+func TestWithRetryGivesUpOnNonRetryableError(t *testing.T) {
+
+	attempts := 0
+	wantErr := &mysql.MySQLError{Number: 1062} // duplicate key, not retryable
+
+	err := withRetry(context.Background(), RetryPolicy{MaxRetries: 3, InitialBackoff: time.Millisecond}, func() error {
+		attempts++
+		return wantErr
+	})
+
+	if err != wantErr {
+		t.Errorf("expected the original error back, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected a single attempt for a non-retryable error, got %v", attempts)
+	}
+}